@@ -19,9 +19,11 @@ package apilogging
 import (
 	"context"
 	"errors"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httputil"
+	"time"
 
 	"google.golang.org/api/option"
 	htransport "google.golang.org/api/transport/http"
@@ -34,15 +36,62 @@ type LoggerConfig struct {
 	Scopes []string
 	// Logging must contain an instance of a logger.
 	Logger *log.Logger
+	// Sink, if set, receives a typed ExchangeRecord for every exchange that
+	// passes LogRequest/LogResponse, instead of the historical REQUEST/RESPONSE
+	// text blocks. If nil, a StdLogSink wrapping Logger is used, which
+	// keeps that historical block format.
+	Sink LogSink
 	// CaptureFullRequest governs whether the body of the request is captured.
 	CaptureFullRequest bool
 	// CaptureFullResponse governs whether the body of the response is captured.
 	CaptureFullResponse bool
+	// CaptureWriter, if set alongside CaptureFullRequest, receives the
+	// request body as it's streamed to the wrapped RoundTripper instead of
+	// having it buffered in memory by httputil.DumpRequest. Useful for large
+	// uploads, e.g. BigQuery row payloads, where buffering the full body
+	// isn't desirable.
+	//
+	// WARNING: bytes sent to CaptureWriter are the raw request body, as
+	// read off the wire. Neither BodyRedactor nor MaxBodyBytes applies to
+	// this path, since both require buffering the full body to inspect or
+	// cap it, which is exactly what streaming to CaptureWriter is meant to
+	// avoid. Do not point CaptureWriter at a log or any other destination
+	// that isn't already trusted with whatever the request body contains,
+	// including credentials.
+	CaptureWriter io.Writer
+	// MaxBodyBytes caps how much of a request/response body is retained on
+	// the logged ExchangeRecord and REQUEST/RESPONSE dump. Bodies longer
+	// than this are truncated with a trailing marker noting how much was
+	// cut. Zero means no cap.
+	MaxBodyBytes int
+	// HeaderRedactor reports whether a header's value should be replaced
+	// with "<redacted>" before logging. If nil, DefaultHeaderRedactor is
+	// used, which redacts common credential-bearing headers such as
+	// Authorization and Cookie.
+	HeaderRedactor func(key string) bool
+	// BodyRedactor, if set, rewrites a request/response body before
+	// logging, given its Content-Type. DefaultBodyRedactor redacts common
+	// credential-bearing JSON fields such as access_token. Unlike
+	// HeaderRedactor, there's no default: body shapes vary too much across
+	// APIs to safely redact without one being configured.
+	BodyRedactor func(contentType string, body []byte) []byte
 	// LogRequest allows filtration based on the request body bytes.
 	LogRequest func(b []byte) bool
 	// LogResponse allows filtration based on the response body bytes.  Whether the
 	// request was matched is also available.
 	LogResponse func(b []byte, requestMatched bool) bool
+	// RetryObserver, if set, is called after each attempt beyond the first
+	// for a given logical call, e.g. when a gensupport-style backoff loop
+	// retries the same *http.Request. It receives the correlation ID for
+	// the call, the attempt number just completed, the HTTP status of the
+	// prior attempt, and an estimate of the next backoff delay.
+	//
+	// Setting this enables correlation tracking for every request, which
+	// hashes the method, URL, and body to recognize retries of the same
+	// logical call; leave it nil (the default) to skip that work
+	// entirely. See WithCorrelationID for callers that need to avoid or
+	// override the hash-based correlation this performs.
+	RetryObserver func(correlationID string, attempt int, lastStatus int, nextBackoff time.Duration)
 }
 
 var defaultScopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
@@ -89,31 +138,124 @@ type interceptor struct {
 }
 
 func (i interceptor) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
 
-	// Capture and evaluate the outgoing request.
-	dumpReq, err := httputil.DumpRequest(r, i.cfg.CaptureFullRequest)
+	// Correlate this attempt with any prior attempts against the same
+	// logical call, so retries can be tied together in the log output.
+	// correlationStateFor never modifies r: a gensupport-style retry loop
+	// rebuilds its *http.Request from scratch on every attempt, so state
+	// can't be persisted by mutating this attempt's copy.
+	//
+	// This is skipped unless something will actually consume it: otherwise
+	// every request, including large streamed uploads, would pay for
+	// re-reading its body via GetBody to hash it, plus a registry mutex and
+	// TTL sweep, for correlation data nothing reads.
+	var cs *correlationState
+	var correlationID string
+	attempt, lastStatus, cumulativeElapsed := 1, 0, time.Duration(0)
+	if i.cfg.RetryObserver != nil || hasExplicitCorrelationID(r.Context()) {
+		cs = correlationStateFor(r)
+		correlationID = cs.id
+		attempt, lastStatus, cumulativeElapsed = cs.beginAttempt()
+	}
+
+	// When a CaptureWriter is configured, stream the request body to it as
+	// it's sent instead of asking DumpRequest to buffer the whole thing.
+	captureFullRequest := i.cfg.CaptureFullRequest
+	if captureFullRequest && i.cfg.CaptureWriter != nil && r.Body != nil {
+		r.Body = &teeReadCloser{r: io.TeeReader(r.Body, i.cfg.CaptureWriter), c: r.Body}
+		captureFullRequest = false
+	}
+
+	// Dump from a clone carrying the correlation ID header, so the REQUEST
+	// entry in the log shows which logical call this attempt belongs to
+	// without modifying the request actually sent, per the
+	// http.RoundTripper contract ("RoundTrip should not modify the
+	// request"). DumpRequest drains and replaces whatever body it's
+	// handed, so the replayable replacement it produces is carried back
+	// onto r afterward.
+	dumpSource := r.Clone(r.Context())
+	if cs != nil {
+		dumpSource.Header.Set(correlationIDHeader, correlationID)
+	}
+	dumpReq, err := httputil.DumpRequest(dumpSource, captureFullRequest)
 	if err != nil {
 		return nil, err
 	}
-	matchedReq := false
-	if i.cfg.LogRequest == nil || i.cfg.LogRequest(dumpReq) {
-		matchedReq = true
-		i.cfg.Logger.Printf("REQUEST\n=====\n%s\n=====\n", dumpReq)
-	}
+	r.Body = dumpSource.Body
+	matchedReq := i.cfg.LogRequest == nil || i.cfg.LogRequest(dumpReq)
 
 	// Invoke the real roundtripper
 	resp, err := i.rt.RoundTrip(r)
 	if err != nil {
 		return resp, err
 	}
+	if cs != nil {
+		cs.recordStatus(resp.StatusCode)
+	}
+
+	if attempt > 1 && i.cfg.RetryObserver != nil {
+		i.cfg.RetryObserver(correlationID, attempt, lastStatus, estimateNextBackoff(attempt))
+	}
 
 	// Now capture/evaluate the response.
 	dumpResp, err := httputil.DumpResponse(resp, i.cfg.CaptureFullResponse)
 	if err != nil {
 		return nil, err
 	}
-	if i.cfg.LogResponse == nil || i.cfg.LogResponse(dumpResp, matchedReq) {
-		i.cfg.Logger.Printf("RESPONSE\n=====\n%s\n=====\n", dumpResp)
+	matchedResp := i.cfg.LogResponse == nil || i.cfg.LogResponse(dumpResp, matchedReq)
+
+	if matchedReq || matchedResp {
+		rec := &ExchangeRecord{
+			Method:            r.Method,
+			URL:               r.URL.String(),
+			Status:            resp.StatusCode,
+			ResponseHeader:    redactHeader(cloneHeader(resp.Header), i.cfg.headerRedactor()),
+			StartTime:         start,
+			Duration:          time.Since(start),
+			BytesReceived:     int64(len(dumpResp)),
+			TraceID:           traceIDFromContext(r.Context()),
+			CorrelationID:     correlationID,
+			Attempt:           attempt,
+			PriorStatus:       lastStatus,
+			CumulativeElapsed: cumulativeElapsed,
+		}
+		if matchedReq {
+			dumpReq = i.cfg.redactDump(dumpReq, r.Header.Get("Content-Type"))
+			rec.RequestHeader = redactHeader(cloneHeader(r.Header), i.cfg.headerRedactor())
+			rec.RawRequest = dumpReq
+			rec.BytesSent = int64(len(dumpReq))
+			if captureFullRequest {
+				rec.RequestBody = splitHeaderBody(dumpReq)
+			}
+		}
+		if matchedResp {
+			dumpResp = i.cfg.redactDump(dumpResp, resp.Header.Get("Content-Type"))
+			rec.RawResponse = dumpResp
+			if i.cfg.CaptureFullResponse {
+				rec.ResponseBody = splitHeaderBody(dumpResp)
+			}
+		}
+		i.sink().LogExchange(r.Context(), rec)
 	}
 	return resp, nil
 }
+
+// teeReadCloser tees reads of r to a configured CaptureWriter while
+// delegating Close to the original body.
+type teeReadCloser struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) { return t.r.Read(p) }
+func (t *teeReadCloser) Close() error               { return t.c.Close() }
+
+// sink returns the configured LogSink, or a StdLogSink wrapping cfg.Logger
+// if none was set.
+func (i interceptor) sink() LogSink {
+	if i.cfg.Sink != nil {
+		return i.cfg.Sink
+	}
+	return &StdLogSink{Logger: i.cfg.Logger}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/logging"
+)
+
+// CloudLoggingSink renders exchanges as Cloud Logging HTTP request log
+// entries via the cloud.google.com/go/logging client, so they render
+// natively in the Logs Explorer's HTTP request view.
+//
+// ProjectID is used to translate the `X-Cloud-Trace-Context`/`traceparent`
+// header on the request into the fully qualified Trace resource name Cloud
+// Logging expects, i.e. "projects/<ProjectID>/traces/<traceID>".
+type CloudLoggingSink struct {
+	Logger    *logging.Logger
+	ProjectID string
+}
+
+// LogExchange implements LogSink.
+func (s *CloudLoggingSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	u, _ := url.Parse(rec.URL)
+	entry := logging.Entry{
+		HTTPRequest: &logging.HTTPRequest{
+			Request: &http.Request{
+				Method: rec.Method,
+				URL:    u,
+				Header: rec.RequestHeader,
+			},
+			Status:       rec.Status,
+			ResponseSize: rec.BytesReceived,
+			Latency:      rec.Duration,
+			UserAgent:    rec.RequestHeader.Get("User-Agent"),
+			RemoteIP:     rec.RequestHeader.Get("X-Forwarded-For"),
+		},
+	}
+	traceID, spanID := traceSpanFromHeader(rec.RequestHeader)
+	if traceID != "" && s.ProjectID != "" {
+		entry.Trace = fmt.Sprintf("projects/%s/traces/%s", s.ProjectID, traceID)
+	}
+	entry.SpanID = spanID
+	s.Logger.Log(entry)
+}
+
+// traceSpanFromHeader extracts a trace/span ID pair from either Google's
+// X-Cloud-Trace-Context header or a W3C traceparent header, preferring the
+// former.
+func traceSpanFromHeader(header map[string][]string) (traceID, spanID string) {
+	h := firstHeaderValue(header, "X-Cloud-Trace-Context")
+	if h != "" {
+		// Format: TRACE_ID/SPAN_ID;o=OPTIONS
+		parts := strings.SplitN(h, "/", 2)
+		traceID = parts[0]
+		if len(parts) == 2 {
+			spanID = strings.SplitN(parts[1], ";", 2)[0]
+		}
+		return traceID, spanID
+	}
+	h = firstHeaderValue(header, "traceparent")
+	if h == "" {
+		return "", ""
+	}
+	// Format: version-traceID-spanID-flags
+	parts := strings.Split(h, "-")
+	if len(parts) != 4 {
+		return "", ""
+	}
+	return parts[1], parts[2]
+}
+
+func firstHeaderValue(header map[string][]string, key string) string {
+	for k, v := range header {
+		if strings.EqualFold(k, key) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTelSink attaches each HTTP exchange as a span event on the span active
+// in the exchange's context, so traffic logged by this package slots into
+// whatever distributed trace the caller's instrumentation already started.
+// It's a no-op if ctx carries no active span.
+type OTelSink struct{}
+
+// LogExchange implements LogSink.
+func (s *OTelSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("http exchange", trace.WithAttributes(
+		attribute.String("http.method", rec.Method),
+		attribute.String("http.url", rec.URL),
+		attribute.Int("http.status_code", rec.Status),
+	))
+}
+
+// RecordStreamEvent adds an OTel span event for a gRPC client stream event
+// to the span active on ctx, carrying rpc.system and rpc.method attributes.
+// It's meant to be called alongside DebugStreamLogger, e.g. via
+// ChainStreamInterceptors, for users who want both the structured slog
+// output and OTel span events for the same stream traffic.
+func RecordStreamEvent(ctx context.Context, method, event string) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent(event, trace.WithAttributes(
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", method),
+	))
+}
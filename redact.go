@@ -0,0 +1,166 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const redactedPlaceholder = "<redacted>"
+
+// defaultRedactedHeaders lists the exact header names DefaultHeaderRedactor
+// redacts. Comparisons are case-insensitive. Headers matching the
+// X-Goog-*-Token pattern, e.g. X-Goog-Iam-Token, aren't listed here;
+// DefaultHeaderRedactor matches them by prefix/suffix.
+var defaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// DefaultHeaderRedactor reports whether key is a header that commonly
+// carries credentials, such as Authorization or Cookie, and so should have
+// its value redacted before logging.
+func DefaultHeaderRedactor(key string) bool {
+	for _, h := range defaultRedactedHeaders {
+		if strings.EqualFold(key, h) {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(key), "x-goog-") && strings.HasSuffix(strings.ToLower(key), "-token")
+}
+
+// defaultRedactedBodyFields lists the top-level JSON field names
+// DefaultBodyRedactor replaces the value of.
+var defaultRedactedBodyFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"password":      true,
+}
+
+// DefaultBodyRedactor redacts well-known credential-bearing fields from JSON
+// request/response bodies. It walks the top-level fields of a JSON object
+// body and replaces the values of fields named in defaultRedactedBodyFields
+// with "<redacted>". Bodies that aren't a JSON object, or whose
+// Content-Type doesn't indicate JSON, are returned unmodified.
+func DefaultBodyRedactor(contentType string, body []byte) []byte {
+	if !strings.Contains(contentType, "json") {
+		return body
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	redacted := false
+	for k := range fields {
+		if defaultRedactedBodyFields[k] {
+			fields[k] = []byte(`"` + redactedPlaceholder + `"`)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// headerRedactor returns cfg's HeaderRedactor, or DefaultHeaderRedactor if
+// none was configured.
+func (cfg *LoggerConfig) headerRedactor() func(key string) bool {
+	if cfg.HeaderRedactor != nil {
+		return cfg.HeaderRedactor
+	}
+	return DefaultHeaderRedactor
+}
+
+// cloneHeader returns a shallow copy of h, so that redaction doesn't mutate
+// the headers actually sent on the wire.
+func cloneHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// redactHeader replaces the value of any header for which shouldRedact
+// returns true with the redacted placeholder, in place.
+func redactHeader(h http.Header, shouldRedact func(key string) bool) http.Header {
+	for k := range h {
+		if shouldRedact(k) {
+			h[k] = []string{redactedPlaceholder}
+		}
+	}
+	return h
+}
+
+// redactDump applies HeaderRedactor, BodyRedactor and MaxBodyBytes to an
+// httputil.DumpRequest/DumpResponse byte block, returning a new block with
+// the same header/body layout.
+func (cfg *LoggerConfig) redactDump(dump []byte, contentType string) []byte {
+	dump = redactHeaderDump(dump, cfg.headerRedactor())
+	idx := bytes.Index(dump, []byte("\r\n\r\n"))
+	if idx < 0 {
+		return dump
+	}
+	header := dump[:idx+4]
+	body := cfg.redactBody(dump[idx+4:], contentType)
+	out := make([]byte, 0, len(header)+len(body))
+	out = append(out, header...)
+	out = append(out, body...)
+	return out
+}
+
+// redactBody applies BodyRedactor, then truncates to MaxBodyBytes if set.
+func (cfg *LoggerConfig) redactBody(body []byte, contentType string) []byte {
+	if cfg.BodyRedactor != nil {
+		body = cfg.BodyRedactor(contentType, body)
+	}
+	if cfg.MaxBodyBytes > 0 && len(body) > cfg.MaxBodyBytes {
+		cut := len(body) - cfg.MaxBodyBytes
+		body = append(append([]byte(nil), body[:cfg.MaxBodyBytes]...), []byte(fmt.Sprintf("... [%d bytes truncated]", cut))...)
+	}
+	return body
+}
+
+// redactHeaderDump rewrites the header lines of an httputil dump, replacing
+// the value of any header for which shouldRedact returns true. It leaves
+// the request/status line and the body untouched.
+func redactHeaderDump(dump []byte, shouldRedact func(key string) bool) []byte {
+	lines := bytes.Split(dump, []byte("\r\n"))
+	for i := 1; i < len(lines); i++ {
+		if len(lines[i]) == 0 {
+			break // blank line marks the end of the header block
+		}
+		parts := bytes.SplitN(lines[i], []byte(":"), 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := string(bytes.TrimSpace(parts[0]))
+		if shouldRedact(key) {
+			lines[i] = []byte(key + ": " + redactedPlaceholder)
+		}
+	}
+	return bytes.Join(lines, []byte("\r\n"))
+}
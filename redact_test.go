@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultHeaderRedactor(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"Authorization", true},
+		{"authorization", true},
+		{"Proxy-Authorization", true},
+		{"Cookie", true},
+		{"Set-Cookie", true},
+		{"X-Goog-Iam-Token", true},
+		{"x-goog-api-token", true},
+		{"Content-Type", false},
+		{"X-Goog-Request-Id", false},
+	}
+	for _, tc := range tests {
+		if got := DefaultHeaderRedactor(tc.key); got != tc.want {
+			t.Errorf("DefaultHeaderRedactor(%q) = %v, want %v", tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultBodyRedactor(t *testing.T) {
+	in := `{"access_token":"secret","refresh_token":"secret2","password":"hunter2","email":"a@b.com"}`
+	out := string(DefaultBodyRedactor("application/json", []byte(in)))
+	for _, field := range []string{"secret", "secret2", "hunter2"} {
+		if strings.Contains(out, field) {
+			t.Errorf("DefaultBodyRedactor output still contains %q: %s", field, out)
+		}
+	}
+	if !strings.Contains(out, "a@b.com") {
+		t.Errorf("DefaultBodyRedactor should leave unrelated fields alone, got: %s", out)
+	}
+
+	// Non-JSON content types are left untouched.
+	raw := []byte("access_token=secret")
+	if got := DefaultBodyRedactor("application/x-www-form-urlencoded", raw); string(got) != string(raw) {
+		t.Errorf("DefaultBodyRedactor modified a non-JSON body: %s", got)
+	}
+
+	// Malformed JSON is returned unmodified rather than dropped.
+	malformed := []byte("{not json")
+	if got := DefaultBodyRedactor("application/json", malformed); string(got) != string(malformed) {
+		t.Errorf("DefaultBodyRedactor should pass through malformed JSON unchanged, got: %s", got)
+	}
+}
+
+func TestRedactHeaderDump(t *testing.T) {
+	dump := "GET / HTTP/1.1\r\nAuthorization: Bearer abc123\r\nContent-Type: application/json\r\n\r\n{\"Authorization\":\"not a header\"}"
+	got := string(redactHeaderDump([]byte(dump), DefaultHeaderRedactor))
+	if strings.Contains(got, "abc123") {
+		t.Errorf("redactHeaderDump left the Authorization header value in place: %s", got)
+	}
+	if !strings.Contains(got, `"Authorization":"not a header"`) {
+		t.Errorf("redactHeaderDump should leave body content alone, got: %s", got)
+	}
+	if !strings.Contains(got, "Content-Type: application/json") {
+		t.Errorf("redactHeaderDump should leave non-redacted headers alone, got: %s", got)
+	}
+}
+
+func TestLoggerConfigRedactBodyMaxBodyBytes(t *testing.T) {
+	cfg := &LoggerConfig{MaxBodyBytes: 5}
+	got := cfg.redactBody([]byte("0123456789"), "text/plain")
+	if !strings.HasPrefix(string(got), "01234") {
+		t.Errorf("redactBody truncated output should start with the first MaxBodyBytes bytes, got: %s", got)
+	}
+	if !strings.Contains(string(got), "5 bytes truncated") {
+		t.Errorf("redactBody should note how many bytes were truncated, got: %s", got)
+	}
+
+	// Under the cap, the body passes through untouched.
+	cfg = &LoggerConfig{MaxBodyBytes: 100}
+	if got := cfg.redactBody([]byte("short"), "text/plain"); string(got) != "short" {
+		t.Errorf("redactBody should leave bodies under the cap alone, got: %s", got)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// HARSink accumulates HTTP exchanges and renders them as a HAR 1.2 document
+// suitable for loading into Chrome DevTools or another HAR viewer. Unlike
+// the other sinks, it doesn't write incrementally: HAR is a single JSON
+// document, so call Flush once traffic capture is complete, e.g. when
+// closing the http.Client using this sink.
+type HARSink struct {
+	mu      sync.Mutex
+	w       io.Writer
+	entries []harEntry
+}
+
+// NewHARSink returns a HARSink that will write its accumulated entries to w
+// when Flush is called.
+func NewHARSink(w io.Writer) *HARSink {
+	return &HARSink{w: w}
+}
+
+// LogExchange implements LogSink.
+func (s *HARSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, harEntryFromRecord(rec))
+}
+
+// Flush writes the accumulated entries as a single HAR 1.2 document.
+func (s *HARSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc := harDocument{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "apilogging", Version: "1.0"},
+			Entries: s.entries,
+		},
+	}
+	return json.NewEncoder(s.w).Encode(doc)
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           harCache    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []harCookie     `json:"cookies"`
+	Headers     []harHeader     `json:"headers"`
+	QueryString []harQueryParam `json:"queryString"`
+	PostData    *harPostData    `json:"postData,omitempty"`
+	HeadersSize int             `json:"headersSize"`
+	BodySize    int             `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []harCookie `json:"cookies"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harQueryParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// harCache is always empty: apilogging has no notion of cached responses,
+// and HAR 1.2 allows an empty object here to mean "no cache information".
+type harCache struct{}
+
+// harTimings is required by HAR 1.2, but ExchangeRecord only tracks total
+// round-trip Duration, not the finer send/wait/receive phases a browser
+// would report. The whole duration is attributed to "wait" accordingly,
+// rather than guessing at a breakdown apilogging has no data for.
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func harEntryFromRecord(rec *ExchangeRecord) harEntry {
+	durationMillis := float64(rec.Duration.Microseconds()) / 1000.0
+	entry := harEntry{
+		StartedDateTime: rec.StartTime.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            durationMillis,
+		Request: harRequest{
+			Method:      rec.Method,
+			URL:         rec.URL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harCookie{},
+			Headers:     harHeaders(rec.RequestHeader),
+			QueryString: []harQueryParam{},
+			HeadersSize: -1,
+			BodySize:    len(rec.RequestBody),
+		},
+		Response: harResponse{
+			Status:      rec.Status,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harCookie{},
+			Headers:     harHeaders(rec.ResponseHeader),
+			Content: harContent{
+				Size:     len(rec.ResponseBody),
+				MimeType: rec.ResponseHeader.Get("Content-Type"),
+				Text:     string(rec.ResponseBody),
+			},
+			HeadersSize: -1,
+			BodySize:    len(rec.ResponseBody),
+		},
+		Cache:   harCache{},
+		Timings: harTimings{Wait: durationMillis},
+	}
+	if len(rec.RequestBody) > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: rec.RequestHeader.Get("Content-Type"),
+			Text:     string(rec.RequestBody),
+		}
+	}
+	return entry
+}
+
+func harHeaders(header map[string][]string) []harHeader {
+	var out []harHeader
+	for k, vs := range header {
+		for _, v := range vs {
+			out = append(out, harHeader{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+// CurlSink renders each matched exchange as a runnable curl command,
+// suitable for interactively replaying a single request during debugging.
+type CurlSink struct {
+	Writer io.Writer
+}
+
+// NewCurlSink returns a CurlSink that writes curl commands to w, one per
+// logged exchange.
+func NewCurlSink(w io.Writer) *CurlSink {
+	return &CurlSink{Writer: w}
+}
+
+// LogExchange implements LogSink.
+func (s *CurlSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "curl -X %s", rec.Method)
+	for k, vs := range rec.RequestHeader {
+		for _, v := range vs {
+			fmt.Fprintf(&b, " -H %s", shellQuote(fmt.Sprintf("%s: %s", k, v)))
+		}
+	}
+	if len(rec.RequestBody) > 0 {
+		b.WriteString(" --data-binary @-")
+	}
+	fmt.Fprintf(&b, " %s", shellQuote(rec.URL))
+	if len(rec.RequestBody) > 0 {
+		// The heredoc redirect must stay on the same logical line as the
+		// curl invocation it feeds; a bare "<<'EOF'" on its own line isn't
+		// attached to the command, so --data-binary @- would read from the
+		// terminal instead.
+		fmt.Fprintf(&b, " <<'EOF'\n%s\nEOF", rec.RequestBody)
+	}
+	b.WriteString("\n")
+	io.WriteString(s.Writer, b.String())
+}
+
+// shellQuote wraps s in single quotes for use as a POSIX shell argument,
+// escaping any single quotes already present in s.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testExchangeRecord() *ExchangeRecord {
+	return &ExchangeRecord{
+		Method:         "POST",
+		URL:            "https://example.com/v1/things",
+		Status:         200,
+		RequestHeader:  http.Header{"Authorization": []string{"Bearer abc"}},
+		ResponseHeader: http.Header{"Content-Type": []string{"application/json"}},
+		RequestBody:    []byte(`{"a":1}`),
+		ResponseBody:   []byte(`{"ok":true}`),
+		StartTime:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:       250 * time.Millisecond,
+	}
+}
+
+func TestHAREntryFromRecord(t *testing.T) {
+	rec := testExchangeRecord()
+	entry := harEntryFromRecord(rec)
+
+	if entry.Request.Method != rec.Method || entry.Request.URL != rec.URL {
+		t.Errorf("harEntryFromRecord request = %+v, want method/url from %+v", entry.Request, rec)
+	}
+	if entry.Response.Status != rec.Status {
+		t.Errorf("harEntryFromRecord response status = %d, want %d", entry.Response.Status, rec.Status)
+	}
+	if entry.Response.Content.MimeType != "application/json" {
+		t.Errorf("harEntryFromRecord response MimeType = %q, want application/json", entry.Response.Content.MimeType)
+	}
+	if entry.Time != 250 {
+		t.Errorf("harEntryFromRecord Time = %v ms, want 250", entry.Time)
+	}
+	if entry.Timings.Wait != 250 {
+		t.Errorf("harEntryFromRecord Timings.Wait = %v ms, want 250", entry.Timings.Wait)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != string(rec.RequestBody) {
+		t.Errorf("harEntryFromRecord PostData = %+v, want text %q", entry.Request.PostData, rec.RequestBody)
+	}
+	if entry.StartedDateTime != "2024-01-02T03:04:05.000Z" {
+		t.Errorf("harEntryFromRecord StartedDateTime = %q", entry.StartedDateTime)
+	}
+}
+
+func TestHARSinkFlush(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewHARSink(&buf)
+	s.LogExchange(context.Background(), testExchangeRecord())
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	var doc harDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Flush produced invalid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Log.Version != "1.2" {
+		t.Errorf("Log.Version = %q, want 1.2", doc.Log.Version)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("Log.Entries has %d entries, want 1", len(doc.Log.Entries))
+	}
+
+	// HAR 1.2 requires "cache" and "timings" on every entry; a validator or
+	// DevTools version that enforces this would reject an entry missing
+	// them.
+	var raw map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("json.Unmarshal raw: %v", err)
+	}
+	entries := raw["log"].(map[string]interface{})["entries"].([]interface{})
+	entry := entries[0].(map[string]interface{})
+	if _, ok := entry["cache"]; !ok {
+		t.Error("HAR entry missing required \"cache\" field")
+	}
+	if _, ok := entry["timings"]; !ok {
+		t.Error("HAR entry missing required \"timings\" field")
+	}
+	if doc.Log.Entries[0].Request.Method != "POST" {
+		t.Errorf("Log.Entries[0].Request.Method = %q, want POST", doc.Log.Entries[0].Request.Method)
+	}
+}
+
+func TestCurlSinkLogExchange(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewCurlSink(&buf)
+	s.LogExchange(context.Background(), testExchangeRecord())
+
+	got := buf.String()
+	if !strings.HasPrefix(got, "curl -X POST") {
+		t.Errorf("curl command = %q, want it to start with 'curl -X POST'", got)
+	}
+	if !strings.Contains(got, "-H 'Authorization: Bearer abc'") {
+		t.Errorf("curl command missing quoted header: %s", got)
+	}
+	if !strings.Contains(got, "'https://example.com/v1/things'") {
+		t.Errorf("curl command missing quoted URL: %s", got)
+	}
+	if !strings.Contains(got, "'https://example.com/v1/things' <<'EOF'\n{\"a\":1}\nEOF\n") {
+		t.Errorf("curl command heredoc must be attached to the same line as the URL: %s", got)
+	}
+	if !strings.Contains(got, "--data-binary @-") {
+		t.Errorf("curl command missing --data-binary flag: %s", got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "'plain'"},
+		{"it's", `'it'\''s'`},
+	}
+	for _, tc := range tests {
+		if got := shellQuote(tc.in); got != tc.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// correlationIDHeader carries the correlation ID for a logical call in the
+// REQUEST dump, so it's visible in the log. It's never sent on the wire:
+// apilogging only ever sets it on a clone used for logging, never on the
+// request actually handed to the wrapped RoundTripper.
+const correlationIDHeader = "X-Apilogging-Correlation-ID"
+
+type correlationIDContextKey struct{}
+
+// WithCorrelationID attaches an explicit correlation ID to ctx, overriding
+// apilogging's default of hashing method+URL+body to group retries of the
+// same logical call together. Use this if the caller's own retry loop
+// derives its context once before the first attempt, since a RoundTripper
+// sits below the retry loop and can't otherwise persist state back onto a
+// request a retry loop will rebuild from scratch on each attempt (as
+// gensupport's backoff loop does).
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey{}, id)
+}
+
+func explicitCorrelationID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDContextKey{}).(string)
+	return id, ok
+}
+
+// hasExplicitCorrelationID reports whether ctx carries a caller-supplied
+// correlation ID via WithCorrelationID. It's used to decide whether
+// correlation tracking should run at all for a request: a plain ctx.Value
+// lookup, unlike correlationKey, costs nothing per-request, so checking it
+// doesn't reintroduce the overhead correlation tracking is otherwise gated
+// behind.
+func hasExplicitCorrelationID(ctx context.Context) bool {
+	_, ok := explicitCorrelationID(ctx)
+	return ok
+}
+
+// correlationState tracks a single logical call across the retries a
+// gensupport-style exponential backoff loop performs. Such loops call
+// client.Do with a fresh shallow copy of the *http.Request on every
+// attempt, so a RoundTripper below them can't persist state by mutating a
+// request's context: the next attempt is rebuilt from the original
+// request, discarding it. Instead, state is keyed by correlationKey, which
+// attempts can each independently recompute to the same value.
+type correlationState struct {
+	mu         sync.Mutex
+	id         string
+	attempt    int
+	lastStatus int
+	start      time.Time
+	lastSeen   time.Time
+}
+
+// beginAttempt records the start of a new attempt, returning the attempt
+// number, the status of the prior attempt (0 if this is the first), and the
+// elapsed time since the first attempt began.
+func (cs *correlationState) beginAttempt() (attempt, lastStatus int, elapsed time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.attempt++
+	cs.lastSeen = time.Now()
+	return cs.attempt, cs.lastStatus, time.Since(cs.start)
+}
+
+func (cs *correlationState) recordStatus(status int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.lastStatus = status
+}
+
+// correlationTTL bounds how long a correlationState is kept around without
+// a new attempt being observed for it, so a long-lived client doesn't leak
+// an entry per logical call that never retries.
+const correlationTTL = 5 * time.Minute
+
+// correlationRegistry holds in-flight correlationState values keyed by
+// correlationKey.
+type correlationRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*correlationState
+}
+
+var globalCorrelationRegistry = &correlationRegistry{entries: make(map[string]*correlationState)}
+
+// stateFor returns the correlationState for key, creating one if this is
+// the first attempt seen for it. It also evicts any entries that have gone
+// quiet for longer than correlationTTL.
+func (reg *correlationRegistry) stateFor(key string) *correlationState {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	cutoff := time.Now().Add(-correlationTTL)
+	for k, cs := range reg.entries {
+		cs.mu.Lock()
+		stale := cs.lastSeen.Before(cutoff)
+		cs.mu.Unlock()
+		if stale {
+			delete(reg.entries, k)
+		}
+	}
+	cs, ok := reg.entries[key]
+	if !ok {
+		now := time.Now()
+		cs = &correlationState{
+			id:       fmt.Sprintf("apilogging_%d", now.UnixNano()), // TODO: real uuid
+			start:    now,
+			lastSeen: now,
+		}
+		reg.entries[key] = cs
+	}
+	return cs
+}
+
+// correlationStateFor returns the correlationState for the logical call r
+// belongs to.
+func correlationStateFor(r *http.Request) *correlationState {
+	return globalCorrelationRegistry.stateFor(correlationKey(r))
+}
+
+// correlationKey computes a stable key for the logical call r belongs to,
+// so that every attempt of a retried call maps to the same
+// correlationState even though each attempt is an independent
+// *http.Request. It's an explicit WithCorrelationID value if the caller set
+// one, otherwise a hash of the method, URL, and body, read via GetBody so
+// the body actually being sent isn't consumed.
+//
+// Hash equality is treated as call identity, not just as a way of
+// recognizing the same retried request: two genuinely independent calls
+// that happen to share a method, URL, and body (e.g. a client polling the
+// same GET on an interval) collide into the same correlationState and are
+// reported as repeated attempts of one logical call rather than as
+// separate calls. Callers for whom that distinction matters should call
+// WithCorrelationID with a value unique per logical call (e.g. a request
+// ID they already generate) rather than relying on this fallback.
+func correlationKey(r *http.Request) string {
+	if id, ok := explicitCorrelationID(r.Context()); ok {
+		return "id:" + id
+	}
+	h := sha256.New()
+	io.WriteString(h, r.Method)
+	io.WriteString(h, "\n")
+	io.WriteString(h, r.URL.String())
+	if r.GetBody != nil {
+		if body, err := r.GetBody(); err == nil {
+			io.Copy(h, body)
+			body.Close()
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// estimateNextBackoff returns a rough exponential backoff estimate for the
+// attempt that just completed. The interceptor has no visibility into the
+// caller's actual retry policy (e.g. gensupport's), so this is only an
+// estimate for RetryObserver's benefit, not the backoff that will actually
+// be used.
+func estimateNextBackoff(attempt int) time.Duration {
+	const (
+		base = 500 * time.Millisecond
+		max  = 30 * time.Second
+	)
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base << uint(attempt-1)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d
+}
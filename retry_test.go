@@ -0,0 +1,131 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateNextBackoff(t *testing.T) {
+	prev := time.Duration(0)
+	for attempt := 1; attempt <= 6; attempt++ {
+		d := estimateNextBackoff(attempt)
+		if d < prev {
+			t.Errorf("estimateNextBackoff(%d) = %s, should not decrease from attempt %d's %s", attempt, d, attempt-1, prev)
+		}
+		if d > 30*time.Second {
+			t.Errorf("estimateNextBackoff(%d) = %s, should be capped at 30s", attempt, d)
+		}
+		prev = d
+	}
+	// Attempts below 1 are clamped, not a negative shift.
+	if d := estimateNextBackoff(0); d <= 0 {
+		t.Errorf("estimateNextBackoff(0) = %s, want a positive clamped duration", d)
+	}
+}
+
+// TestCorrelationKeyMatchesAcrossRetryCopies verifies that correlationKey
+// produces the same value for independent shallow copies of a request, the
+// way a gensupport-style backoff loop rebuilds a request per attempt.
+func TestCorrelationKeyMatchesAcrossRetryCopies(t *testing.T) {
+	makeReq := func() *http.Request {
+		r, err := http.NewRequest("POST", "https://example.com/v1/things", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		return r
+	}
+
+	attempt1 := makeReq()
+	attempt2 := makeReq()
+
+	k1 := correlationKey(attempt1)
+	k2 := correlationKey(attempt2)
+	if k1 != k2 {
+		t.Errorf("correlationKey differed across independent copies of the same logical call: %q != %q", k1, k2)
+	}
+
+	other, err := http.NewRequest("POST", "https://example.com/v1/other", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	if k3 := correlationKey(other); k3 == k1 {
+		t.Errorf("correlationKey should differ for a different URL, got the same key %q", k3)
+	}
+}
+
+func TestCorrelationKeyHonorsExplicitCorrelationID(t *testing.T) {
+	r, err := http.NewRequest("GET", "https://example.com/v1/things", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	r = r.WithContext(WithCorrelationID(r.Context(), "my-call-id"))
+	if got := correlationKey(r); got != "id:my-call-id" {
+		t.Errorf("correlationKey = %q, want the explicit correlation ID to take precedence", got)
+	}
+}
+
+func TestHasExplicitCorrelationID(t *testing.T) {
+	if hasExplicitCorrelationID(context.Background()) {
+		t.Error("hasExplicitCorrelationID should be false for a plain context")
+	}
+	ctx := WithCorrelationID(context.Background(), "my-call-id")
+	if !hasExplicitCorrelationID(ctx) {
+		t.Error("hasExplicitCorrelationID should be true once WithCorrelationID is used")
+	}
+}
+
+func TestCorrelationStateBeginAttempt(t *testing.T) {
+	cs := &correlationState{start: time.Now()}
+	attempt, lastStatus, _ := cs.beginAttempt()
+	if attempt != 1 || lastStatus != 0 {
+		t.Errorf("first beginAttempt = (%d, %d), want (1, 0)", attempt, lastStatus)
+	}
+	cs.recordStatus(503)
+	attempt, lastStatus, _ = cs.beginAttempt()
+	if attempt != 2 || lastStatus != 503 {
+		t.Errorf("second beginAttempt = (%d, %d), want (2, 503)", attempt, lastStatus)
+	}
+}
+
+func TestCorrelationStateForPersistsAcrossRetryCopies(t *testing.T) {
+	makeReq := func() *http.Request {
+		r, err := http.NewRequest("POST", "https://example.com/v1/upload", strings.NewReader("body"))
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		return r
+	}
+
+	cs1 := correlationStateFor(makeReq())
+	attempt, _, _ := cs1.beginAttempt()
+	if attempt != 1 {
+		t.Fatalf("attempt = %d, want 1", attempt)
+	}
+	cs1.recordStatus(500)
+
+	cs2 := correlationStateFor(makeReq())
+	if cs2 != cs1 {
+		t.Fatalf("correlationStateFor returned different state for a retry of the same logical call")
+	}
+	attempt, lastStatus, _ := cs2.beginAttempt()
+	if attempt != 2 || lastStatus != 500 {
+		t.Errorf("retry attempt = (%d, %d), want (2, 500)", attempt, lastStatus)
+	}
+}
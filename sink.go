@@ -0,0 +1,203 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// ExchangeRecord describes a single HTTP request/response exchange as
+// observed by the intercepting RoundTripper. It's handed to a LogSink once
+// per RoundTrip call that the configured LogRequest/LogResponse predicates
+// allow through.
+type ExchangeRecord struct {
+	Method         string
+	URL            string
+	Status         int
+	RequestHeader  http.Header
+	ResponseHeader http.Header
+	// RequestBody and ResponseBody are only populated when the
+	// corresponding CaptureFullRequest/CaptureFullResponse flag is set.
+	RequestBody   []byte
+	ResponseBody  []byte
+	StartTime     time.Time
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+	// TraceID is populated from the request context via WithTraceID, if
+	// the caller's instrumentation has attached one.
+	TraceID string
+
+	// CorrelationID identifies the logical call this exchange belongs to,
+	// shared across every retry attempt made against the same
+	// *http.Request. Attempt is 1 on the first try. PriorStatus is the HTTP
+	// status of the previous attempt, or 0 if this is the first.
+	// CumulativeElapsed is the time elapsed since the first attempt began.
+	// These are only tracked when LoggerConfig.RetryObserver is set or the
+	// request context carries WithCorrelationID; otherwise CorrelationID is
+	// empty, Attempt is always 1, and PriorStatus and CumulativeElapsed are
+	// zero.
+	CorrelationID     string
+	Attempt           int
+	PriorStatus       int
+	CumulativeElapsed time.Duration
+
+	// RawRequest and RawResponse hold the httputil.DumpRequest/DumpResponse
+	// output, preserved for sinks that want the historical wire format.
+	// They are only set when the request/response, respectively, matched
+	// the configured LogRequest/LogResponse predicate.
+	RawRequest  []byte
+	RawResponse []byte
+}
+
+// LogSink receives a fully populated ExchangeRecord for each HTTP exchange
+// that passes the LoggerConfig's LogRequest/LogResponse filters.
+// Implementations should return promptly, as LogExchange is invoked
+// synchronously on the RoundTrip call path.
+type LogSink interface {
+	LogExchange(ctx context.Context, rec *ExchangeRecord)
+}
+
+// StdLogSink renders exchanges using the historical REQUEST/RESPONSE block
+// format. It's the default sink when a LoggerConfig doesn't specify one, so
+// that existing callers that only set Logger see the same block format.
+// Header values are redacted per LoggerConfig.HeaderRedactor (by default
+// DefaultHeaderRedactor), and nothing is logged for a RoundTrip whose
+// wrapped RoundTripper returns an error.
+type StdLogSink struct {
+	Logger *log.Logger
+}
+
+// LogExchange implements LogSink.
+func (s *StdLogSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	if rec.RawRequest != nil {
+		s.Logger.Printf("REQUEST\n=====\n%s\n=====\n", rec.RawRequest)
+	}
+	if rec.RawResponse != nil {
+		s.Logger.Printf("RESPONSE\n=====\n%s\n=====\n", rec.RawResponse)
+	}
+	if rec.Attempt > 1 {
+		s.Logger.Printf("RETRY correlation=%s attempt=%d priorStatus=%d elapsed=%s\n",
+			rec.CorrelationID, rec.Attempt, rec.PriorStatus, rec.CumulativeElapsed)
+	}
+}
+
+// SlogSink renders exchanges via an slog.Handler, following the same
+// structured logging conventions used for gRPC stream events in
+// DebugStreamLogger.
+type SlogSink struct {
+	Handler slog.Handler
+}
+
+// LogExchange implements LogSink.
+func (s *SlogSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	l := slog.New(s.Handler)
+	l.LogAttrs(ctx, slog.LevelInfo, "http exchange",
+		slog.String("Method", rec.Method),
+		slog.String("URL", rec.URL),
+		slog.Int("Status", rec.Status),
+		slog.Duration("Duration", rec.Duration),
+		slog.Int64("BytesSent", rec.BytesSent),
+		slog.Int64("BytesReceived", rec.BytesReceived),
+		slog.String("TraceID", rec.TraceID),
+		slog.String("CorrelationID", rec.CorrelationID),
+		slog.Int("Attempt", rec.Attempt),
+		slog.Int("PriorStatus", rec.PriorStatus),
+		slog.Duration("CumulativeElapsed", rec.CumulativeElapsed),
+	)
+}
+
+// JSONSink writes one JSON object per line, one per exchange, suitable for
+// piping API traffic into structured logging pipelines.
+type JSONSink struct {
+	Writer io.Writer
+}
+
+type jsonExchange struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Status         int         `json:"status"`
+	RequestHeader  http.Header `json:"requestHeader,omitempty"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	RequestBody    string      `json:"requestBody,omitempty"`
+	ResponseBody   string      `json:"responseBody,omitempty"`
+	DurationMillis int64       `json:"durationMillis"`
+	BytesSent      int64       `json:"bytesSent"`
+	BytesReceived  int64       `json:"bytesReceived"`
+	TraceID        string      `json:"traceId,omitempty"`
+	CorrelationID  string      `json:"correlationId,omitempty"`
+	Attempt        int         `json:"attempt,omitempty"`
+	PriorStatus    int         `json:"priorStatus,omitempty"`
+}
+
+// LogExchange implements LogSink.
+func (s *JSONSink) LogExchange(ctx context.Context, rec *ExchangeRecord) {
+	je := jsonExchange{
+		Method:         rec.Method,
+		URL:            rec.URL,
+		Status:         rec.Status,
+		RequestHeader:  rec.RequestHeader,
+		ResponseHeader: rec.ResponseHeader,
+		RequestBody:    string(rec.RequestBody),
+		ResponseBody:   string(rec.ResponseBody),
+		DurationMillis: rec.Duration.Milliseconds(),
+		BytesSent:      rec.BytesSent,
+		BytesReceived:  rec.BytesReceived,
+		TraceID:        rec.TraceID,
+		CorrelationID:  rec.CorrelationID,
+		Attempt:        rec.Attempt,
+		PriorStatus:    rec.PriorStatus,
+	}
+	b, err := json.Marshal(je)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.Writer, "%s\n", b)
+}
+
+type traceIDContextKey struct{}
+
+// WithTraceID attaches a trace/span identifier to ctx so that it's surfaced
+// on the ExchangeRecord for any RoundTrip made with a request carrying that
+// context, e.g. a trace/span ID pulled from an OpenTelemetry span or a Cloud
+// Trace header by the caller's own instrumentation.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey{}, traceID)
+}
+
+func traceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDContextKey{}).(string)
+	return id
+}
+
+// splitHeaderBody returns the body portion of an httputil dump, i.e.
+// everything after the blank line terminating the header block. It returns
+// nil if dump doesn't contain a header/body boundary.
+func splitHeaderBody(dump []byte) []byte {
+	idx := bytes.Index(dump, []byte("\r\n\r\n"))
+	if idx < 0 {
+		return nil
+	}
+	return dump[idx+4:]
+}
@@ -17,8 +17,8 @@ package apilogging
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
-	"unsafe"
 
 	"golang.org/x/exp/slog"
 	"google.golang.org/grpc"
@@ -33,13 +33,14 @@ import (
 //
 //	client, err := managedwriter.NewClient(ctx, projectID, option.WithGRPCDialOption(grpc.WithStreamInterceptor(apilogging.DebugStreamLogger)))
 //
-// Caveat: gRPC by default only allows a single interceptor, but there are specialized interceptors in the wild that
-// enable chaining.
+// Caveat: gRPC by default only allows a single interceptor. Use ChainStreamInterceptors to
+// compose DebugStreamLogger with other stream interceptors, such as auth or retry logic.
 func DebugStreamLogger(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
 	dcs := &debugClientStream{
 		ctx:    ctx,
 		method: method,
 		id:     fmt.Sprintf("debug_stream_%d", time.Now().UnixNano()), // TODO: real uuid,
+		stats:  &StreamStats{},
 	}
 	log := dcs.log(slog.LevelInfo, "intercepting ClientStream")
 	defer log.log()
@@ -62,6 +63,84 @@ type debugClientStream struct {
 	real   grpc.ClientStream
 	id     string
 	method string
+	stats  *StreamStats
+}
+
+// StreamStats summarizes the traffic observed on a single intercepted client
+// stream. It's updated as the stream is used, so callers can retrieve it via
+// StreamStatsFromContext at any point, including after the stream closes, to
+// assert on traffic volumes in tests or metrics exporters.
+type StreamStats struct {
+	mu            sync.Mutex
+	sendCount     int
+	recvCount     int
+	bytesSent     int64
+	bytesReceived int64
+}
+
+func (s *StreamStats) recordSend(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendCount++
+	if size > 0 {
+		s.bytesSent += size
+	}
+}
+
+func (s *StreamStats) recordRecv(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recvCount++
+	if size > 0 {
+		s.bytesReceived += size
+	}
+}
+
+// SendCount reports the number of successful SendMsg calls observed so far.
+// Calls that return an error aren't counted.
+func (s *StreamStats) SendCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sendCount
+}
+
+// RecvCount reports the number of successful RecvMsg calls observed so far.
+// Calls that return an error, including io.EOF at the end of the stream,
+// aren't counted.
+func (s *StreamStats) RecvCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recvCount
+}
+
+// BytesSent reports the cumulative wire size of messages sent so far, as
+// measured by proto.Size. Messages that don't implement proto.Message don't
+// contribute to this total.
+func (s *StreamStats) BytesSent() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bytesSent
+}
+
+// BytesReceived reports the cumulative wire size of messages received so
+// far, as measured by proto.Size. Messages that don't implement
+// proto.Message don't contribute to this total.
+func (s *StreamStats) BytesReceived() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bytesReceived
+}
+
+type streamStatsContextKey struct{}
+
+// StreamStatsFromContext retrieves the StreamStats tracking an intercepted
+// client stream, if any. The returned stats continue to update until the
+// stream is closed. The context must be one returned by
+// debugClientStream.Context, i.e. grpc.ClientStream.Context() on a stream
+// created through DebugStreamLogger.
+func StreamStatsFromContext(ctx context.Context) (*StreamStats, bool) {
+	stats, ok := ctx.Value(streamStatsContextKey{}).(*StreamStats)
+	return stats, ok
 }
 
 type logData struct {
@@ -116,6 +195,12 @@ func (dcs *debugClientStream) CloseSend() error {
 	if err != nil {
 		log.addAttr(slog.Any("Error", err))
 	}
+	// Emit a stream summary alongside the CloseSend event, so totals are
+	// visible without needing to retrieve StreamStats separately.
+	log.addAttr(slog.Int("SendCount", dcs.stats.SendCount()))
+	log.addAttr(slog.Int("RecvCount", dcs.stats.RecvCount()))
+	log.addAttr(slog.Int64("BytesSent", dcs.stats.BytesSent()))
+	log.addAttr(slog.Int64("BytesReceived", dcs.stats.BytesReceived()))
 	return err
 }
 
@@ -123,29 +208,39 @@ func (dcs *debugClientStream) Context() context.Context {
 	log := dcs.log(slog.LevelInfo, "ClientStream event")
 	defer log.log()
 	log.addAttr(slog.String(clientStreamMethodName, "Context"))
-	return dcs.real.Context()
+	return context.WithValue(dcs.real.Context(), streamStatsContextKey{}, dcs.stats)
 }
 
 func (dcs *debugClientStream) SendMsg(m interface{}) error {
+	start := time.Now()
 	log := dcs.log(slog.LevelInfo, "ClientStream event")
 	defer log.log()
 	log.addAttr(slog.String(clientStreamMethodName, "SendMsg"))
-	log.addAttr(slog.Int64("MessageSize", int64(unsafe.Sizeof(m))))
+	size := messageSize(m)
+	log.addAttr(slog.Int64("MessageSize", size))
 	err := dcs.real.SendMsg(m)
+	log.addAttr(slog.Duration("Duration", time.Since(start)))
 	if err != nil {
 		log.addAttr(slog.Any("Error", err))
+		return err
 	}
-	return err
+	dcs.stats.recordSend(size)
+	return nil
 }
 
 func (dcs *debugClientStream) RecvMsg(m interface{}) error {
+	start := time.Now()
 	log := dcs.log(slog.LevelInfo, "ClientStream event")
 	defer log.log()
 	log.addAttr(slog.String(clientStreamMethodName, "RecvMsg"))
-	log.addAttr(slog.Int64("MessageSize", int64(unsafe.Sizeof(m))))
 	err := dcs.real.RecvMsg(m)
+	size := messageSize(m)
+	log.addAttr(slog.Int64("MessageSize", size))
+	log.addAttr(slog.Duration("Duration", time.Since(start)))
 	if err != nil {
 		log.addAttr(slog.Any("Error", err))
+		return err
 	}
-	return err
+	dcs.stats.recordRecv(size)
+	return nil
 }
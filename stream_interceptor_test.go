@@ -0,0 +1,105 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestStreamStatsAccumulate(t *testing.T) {
+	s := &StreamStats{}
+	s.recordSend(10)
+	s.recordSend(5)
+	s.recordRecv(7)
+
+	if got := s.SendCount(); got != 2 {
+		t.Errorf("SendCount() = %d, want 2", got)
+	}
+	if got := s.RecvCount(); got != 1 {
+		t.Errorf("RecvCount() = %d, want 1", got)
+	}
+	if got := s.BytesSent(); got != 15 {
+		t.Errorf("BytesSent() = %d, want 15", got)
+	}
+	if got := s.BytesReceived(); got != 7 {
+		t.Errorf("BytesReceived() = %d, want 7", got)
+	}
+}
+
+func TestStreamStatsIgnoresNonPositiveSize(t *testing.T) {
+	s := &StreamStats{}
+	s.recordSend(-1)
+	if got := s.SendCount(); got != 1 {
+		t.Errorf("SendCount() = %d, want 1", got)
+	}
+	if got := s.BytesSent(); got != 0 {
+		t.Errorf("BytesSent() = %d, want 0 for a non-proto message", got)
+	}
+}
+
+// fakeClientStream is a minimal grpc.ClientStream used to drive
+// debugClientStream without a real connection.
+type fakeClientStream struct {
+	ctx context.Context
+}
+
+func (f *fakeClientStream) Header() (metadata.MD, error) { return nil, nil }
+func (f *fakeClientStream) Trailer() metadata.MD         { return nil }
+func (f *fakeClientStream) CloseSend() error             { return nil }
+func (f *fakeClientStream) Context() context.Context     { return f.ctx }
+func (f *fakeClientStream) SendMsg(m interface{}) error  { return nil }
+func (f *fakeClientStream) RecvMsg(m interface{}) error  { return nil }
+
+func TestDebugClientStreamTracksStats(t *testing.T) {
+	dcs := &debugClientStream{
+		ctx:    context.Background(),
+		method: "/test.Service/Stream",
+		id:     "debug_stream_test",
+		stats:  &StreamStats{},
+		real:   &fakeClientStream{ctx: context.Background()},
+	}
+
+	if err := dcs.SendMsg(durationpb.New(0)); err != nil {
+		t.Fatalf("SendMsg: %v", err)
+	}
+	if err := dcs.RecvMsg(durationpb.New(0)); err != nil {
+		t.Fatalf("RecvMsg: %v", err)
+	}
+
+	if got := dcs.stats.SendCount(); got != 1 {
+		t.Errorf("SendCount() = %d, want 1", got)
+	}
+	if got := dcs.stats.RecvCount(); got != 1 {
+		t.Errorf("RecvCount() = %d, want 1", got)
+	}
+
+	stats, ok := StreamStatsFromContext(dcs.Context())
+	if !ok {
+		t.Fatal("StreamStatsFromContext did not find stats on the stream's context")
+	}
+	if stats != dcs.stats {
+		t.Error("StreamStatsFromContext returned a different *StreamStats than the stream's own")
+	}
+}
+
+func TestStreamStatsFromContextMissing(t *testing.T) {
+	if _, ok := StreamStatsFromContext(context.Background()); ok {
+		t.Error("StreamStatsFromContext should report false for a context without stats")
+	}
+}
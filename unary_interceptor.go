@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// DebugUnaryLogger is a gRPC client unary interceptor suitable for logging
+// activity related to unary gRPC calls. Like DebugStreamLogger, it uses
+// golang.org/x/exp/slog for logging.
+//
+// To use this with an existing client, pass the appropriate ClientOption to
+// register this interceptor. For example:
+//
+//	client, err := someapi.NewClient(ctx, option.WithGRPCDialOption(grpc.WithUnaryInterceptor(apilogging.DebugUnaryLogger)))
+func DebugUnaryLogger(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+
+	attrs := []slog.Attr{
+		slog.String("DebugID", fmt.Sprintf("debug_unary_%d", time.Now().UnixNano())), // TODO: real uuid
+		slog.String("Method", method),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs, slog.Time("Deadline", deadline))
+	}
+	attrs = append(attrs, slog.Int64("RequestSize", messageSize(req)))
+
+	// The peer address is only populated via the grpc.Peer call option,
+	// not via peer.FromContext, which is a server-side mechanism for
+	// incoming RPCs.
+	var p peer.Peer
+	opts = append(opts, grpc.Peer(&p))
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	attrs = append(attrs,
+		slog.Int64("ResponseSize", messageSize(reply)),
+		slog.Duration("Duration", time.Since(start)),
+		slog.String("StatusCode", status.Code(err).String()),
+	)
+	if p.Addr != nil {
+		attrs = append(attrs, slog.String("Peer", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("Error", err))
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "intercepting unary call", attrs...)
+	return err
+}
+
+// messageSize reports the wire size of a proto.Message payload, or -1 if m
+// doesn't implement proto.Message.
+func messageSize(m interface{}) int64 {
+	pm, ok := m.(proto.Message)
+	if !ok {
+		return -1
+	}
+	return int64(proto.Size(pm))
+}
+
+// ChainUnaryInterceptors composes multiple grpc.UnaryClientInterceptor values
+// into a single interceptor, invoked in the order supplied, so that logging
+// interceptors like DebugUnaryLogger can be combined with auth, retry, or
+// tracing interceptors.
+func ChainUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		chained := invoker
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor := interceptors[i]
+			next := chained
+			chained = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return interceptor(ctx, method, req, reply, cc, next, opts...)
+			}
+		}
+		return chained(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// ChainStreamInterceptors composes multiple grpc.StreamClientInterceptor
+// values into a single interceptor, invoked in the order supplied. This
+// allows DebugStreamLogger to be combined with other stream interceptors,
+// working around the fact that grpc.Dial only accepts a single
+// grpc.StreamClientInterceptor option.
+func ChainStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		chained := streamer
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor := interceptors[i]
+			next := chained
+			chained = func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return interceptor(ctx, desc, cc, method, next, opts...)
+			}
+		}
+		return chained(ctx, desc, cc, method, opts...)
+	}
+}
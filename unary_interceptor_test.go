@@ -0,0 +1,93 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apilogging
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestMessageSize(t *testing.T) {
+	msg := durationpb.New(0)
+	if got := messageSize(msg); got < 0 {
+		t.Errorf("messageSize(proto.Message) = %d, want a non-negative wire size", got)
+	}
+	if got := messageSize("not a proto message"); got != -1 {
+		t.Errorf("messageSize(non-proto) = %d, want -1", got)
+	}
+}
+
+func TestChainUnaryInterceptorsOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.UnaryClientInterceptor {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			calls = append(calls, name+":before")
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			calls = append(calls, name+":after")
+			return err
+		}
+	}
+	chained := ChainUnaryInterceptors(record("a"), record("b"))
+	terminal := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls = append(calls, "invoker")
+		return nil
+	}
+	if err := chained(context.Background(), "/m", nil, nil, nil, terminal); err != nil {
+		t.Fatalf("chained interceptor returned error: %v", err)
+	}
+
+	want := []string{"a:before", "b:before", "invoker", "b:after", "a:after"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q (full: %v)", i, calls[i], want[i], calls)
+		}
+	}
+}
+
+func TestChainStreamInterceptorsOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.StreamClientInterceptor {
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			calls = append(calls, name+":before")
+			cs, err := streamer(ctx, desc, cc, method, opts...)
+			calls = append(calls, name+":after")
+			return cs, err
+		}
+	}
+	chained := ChainStreamInterceptors(record("a"), record("b"))
+	terminal := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		calls = append(calls, "streamer")
+		return nil, nil
+	}
+	if _, err := chained(context.Background(), &grpc.StreamDesc{}, nil, "/m", terminal); err != nil {
+		t.Fatalf("chained interceptor returned error: %v", err)
+	}
+
+	want := []string{"a:before", "b:before", "streamer", "b:after", "a:after"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q (full: %v)", i, calls[i], want[i], calls)
+		}
+	}
+}